@@ -0,0 +1,142 @@
+package memcache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Binary protocol SASL auth constants, per the memcached binary protocol
+// spec: a 24-byte header (magic, opcode, key length, ..., total body
+// length, ...) followed by key and value. binaryMagicResponse is the magic
+// byte every binary-protocol response header carries, which lets a reply be
+// told apart from an ASCII-only server echoing something else back (e.g.
+// "ERROR\r\n").
+const (
+	binaryMagicRequest  = 0x80
+	binaryMagicResponse = 0x81
+	binaryOpSASLAuth    = 0x21
+	binaryHeaderLen     = 24
+)
+
+// authenticate performs the configured SASL handshake before the caller
+// issues "config get cluster", returning the connection and buffered
+// read/writer the caller should continue using. It is a no-op when no
+// username is configured.
+//
+// It first tries the binary-protocol handshake, bounded by a read deadline
+// of s.dialTimeout so an ASCII-only server that doesn't recognize the binary
+// opcode (and simply leaves the connection open) can't hang this call
+// forever. With a zero dialTimeout no deadline is set. If that
+// fails, conn is discarded and authenticate reconnects before trying the
+// ASCII fallback, since the binary attempt may have left the original
+// connection in an indeterminate state.
+func (s *memcachedAutoDiscovery) authenticate(conn net.Conn, address string) (net.Conn, *bufio.ReadWriter, error) {
+	if s.username == "" {
+		return conn, bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)), nil
+	}
+
+	mechanism := s.authMechanism
+	if mechanism == "" {
+		mechanism = AuthMechanismPlain
+	}
+	if mechanism != AuthMechanismPlain {
+		return conn, nil, fmt.Errorf("memcache: unsupported auth mechanism %q", mechanism)
+	}
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	if err := s.saslAuthBinary(conn, rw); err == nil {
+		return conn, rw, nil
+	}
+
+	if err := conn.Close(); err != nil {
+		return nil, nil, err
+	}
+	conn, err := s.dial(address)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rw = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	if err := s.saslAuthASCII(rw); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, rw, nil
+}
+
+// saslAuthBinary issues a binary-protocol SASL PLAIN auth request over rw: a
+// key of "PLAIN" and a body of "\x00<username>\x00<password>".
+func (s *memcachedAutoDiscovery) saslAuthBinary(conn net.Conn, rw *bufio.ReadWriter) error {
+	if s.dialTimeout > 0 {
+		if err := conn.SetReadDeadline(time.Now().Add(s.dialTimeout)); err != nil {
+			return err
+		}
+		defer conn.SetReadDeadline(time.Time{})
+	}
+
+	mechanism := []byte(AuthMechanismPlain)
+	body := []byte("\x00" + s.username + "\x00" + s.password)
+
+	header := make([]byte, binaryHeaderLen)
+	header[0] = binaryMagicRequest
+	header[1] = binaryOpSASLAuth
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(mechanism)))
+	binary.BigEndian.PutUint32(header[8:12], uint32(len(mechanism)+len(body)))
+
+	if _, err := rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := rw.Write(mechanism); err != nil {
+		return err
+	}
+	if _, err := rw.Write(body); err != nil {
+		return err
+	}
+	if err := rw.Flush(); err != nil {
+		return err
+	}
+
+	respHeader := make([]byte, binaryHeaderLen)
+	if _, err := io.ReadFull(rw, respHeader); err != nil {
+		return err
+	}
+	if respHeader[0] != binaryMagicResponse {
+		return fmt.Errorf("memcache: not a binary-protocol response (magic 0x%x)", respHeader[0])
+	}
+	status := binary.BigEndian.Uint16(respHeader[6:8])
+	bodyLen := binary.BigEndian.Uint32(respHeader[8:12])
+	if bodyLen > 0 {
+		if _, err := io.CopyN(io.Discard, rw, int64(bodyLen)); err != nil {
+			return err
+		}
+	}
+	if status != 0 {
+		return fmt.Errorf("memcache: SASL auth failed with status 0x%x", status)
+	}
+	return nil
+}
+
+// saslAuthASCII is the fallback for servers that only speak the ASCII
+// protocol: it issues "set auth <user> <pass>" and expects "STORED".
+func (s *memcachedAutoDiscovery) saslAuthASCII(rw *bufio.ReadWriter) error {
+	if _, err := fmt.Fprintf(rw, "set auth %s %s\r\n", s.username, s.password); err != nil {
+		return err
+	}
+	if err := rw.Flush(); err != nil {
+		return err
+	}
+
+	line, err := rw.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if reply := strings.TrimSpace(line); reply != "STORED" {
+		return fmt.Errorf("memcache: ASCII auth failed: %s", reply)
+	}
+	return nil
+}
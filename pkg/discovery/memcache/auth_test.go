@@ -0,0 +1,130 @@
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// generateTestCert creates a self-signed certificate valid for 127.0.0.1,
+// entirely in-memory, for the TLS integration test below.
+func generateTestCert(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %s", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// stubSASLServer accepts one TLS connection, answers a binary SASL auth
+// request with success, then answers "config get cluster" with a scripted
+// config, standing in for a real ElastiCache Serverless endpoint behind TLS.
+func stubSASLServer(t *testing.T, cert tls.Certificate) net.Listener {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		header := make([]byte, binaryHeaderLen)
+		if _, err := io.ReadFull(rw, header); err != nil {
+			return
+		}
+		bodyLen := binary.BigEndian.Uint32(header[8:12])
+		if _, err := io.CopyN(io.Discard, rw, int64(bodyLen)); err != nil {
+			return
+		}
+
+		resp := make([]byte, binaryHeaderLen)
+		resp[0] = binaryMagicResponse
+		resp[1] = binaryOpSASLAuth
+		if _, err := rw.Write(resp); err != nil {
+			return
+		}
+		if err := rw.Flush(); err != nil {
+			return
+		}
+
+		line, err := rw.ReadString('\n')
+		if err != nil || line != "config get cluster\n" {
+			return
+		}
+
+		body := "1\nnode1.cache|10.0.0.1|11211\n"
+		fmt.Fprintf(rw, "CONFIG cluster 0 %d\n%s", len(body), body)
+		rw.Flush()
+	}()
+
+	return ln
+}
+
+func TestMemcachedAutoDiscovery_Resolve_TLSWithSASL(t *testing.T) {
+	cert := generateTestCert(t)
+	ln := stubSASLServer(t, cert)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert.Leaf)
+
+	r := &memcachedAutoDiscovery{
+		dialTimeout: time.Second,
+		tlsConfig:   &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"},
+		username:    "user",
+		password:    "pass",
+	}
+
+	config, err := r.Resolve(context.Background(), ln.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if config.version != 1 {
+		t.Fatalf("expected version 1, got %d", config.version)
+	}
+	if len(config.nodes) != 1 || config.nodes[0].ip != "10.0.0.1" {
+		t.Fatalf("unexpected nodes: %+v", config.nodes)
+	}
+}
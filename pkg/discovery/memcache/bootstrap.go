@@ -0,0 +1,179 @@
+package memcache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMemcachedPort is applied to any seed host in a connection string
+// that doesn't specify its own port.
+const defaultMemcachedPort = 11211
+
+// discoverySchemes maps the short "discovery" mode named in a connection
+// string to the scheme it's registered under in this package's registry.
+var discoverySchemes = map[string]string{
+	"auto":   "autodiscovery+tcp",
+	"dns":    "dns",
+	"dnssrv": "dnssrv",
+	"static": "static",
+}
+
+// BootstrapHost is one seed host parsed out of a connection string.
+type BootstrapHost struct {
+	Host string
+	Port int
+}
+
+// BootstrapSpec is the parsed form of a memcached connection string, e.g.
+// "memcached://h1:11211,h2:11211,h3/?discovery=auto&dial_timeout=2s&refresh=30s".
+type BootstrapSpec struct {
+	// Hosts is the ordered list of seed hosts to try, in the order given in
+	// the connection string.
+	Hosts []BootstrapHost
+
+	// Discovery is the discovery mode requested ("auto", "dns", "dnssrv" or
+	// "static"); it defaults to "auto".
+	Discovery string
+
+	DialTimeout time.Duration
+	Refresh     time.Duration
+}
+
+// ParseConnectionString parses a memcached connection string into a
+// BootstrapSpec, in the spirit of Couchbase's gocbconnstr. Hosts without an
+// explicit port default to defaultMemcachedPort.
+//
+// The host list is comma-separated ("h1:11211,h2:11211,h3"), which isn't
+// valid in a standard net/url authority, so the scheme, host list and query
+// are split by hand rather than via url.Parse.
+func ParseConnectionString(s string) (*BootstrapSpec, error) {
+	const prefix = "memcached://"
+	if !strings.HasPrefix(s, prefix) {
+		return nil, fmt.Errorf("memcache: connection string %q must use the memcached:// scheme", s)
+	}
+	rest := strings.TrimPrefix(s, prefix)
+
+	var rawQuery string
+	if idx := strings.Index(rest, "?"); idx >= 0 {
+		rawQuery = rest[idx+1:]
+		rest = rest[:idx]
+	}
+	rest = strings.TrimSuffix(rest, "/")
+
+	spec := &BootstrapSpec{Discovery: "auto"}
+
+	for _, hostport := range strings.Split(rest, ",") {
+		if hostport == "" {
+			continue
+		}
+
+		host, portStr, err := net.SplitHostPort(hostport)
+		if err != nil {
+			host, portStr = hostport, ""
+		}
+
+		port := defaultMemcachedPort
+		if portStr != "" {
+			port, err = strconv.Atoi(portStr)
+			if err != nil {
+				return nil, fmt.Errorf("memcache: invalid port in %q: %w", hostport, err)
+			}
+		}
+
+		spec.Hosts = append(spec.Hosts, BootstrapHost{Host: host, Port: port})
+	}
+	if len(spec.Hosts) == 0 {
+		return nil, fmt.Errorf("memcache: connection string %q has no hosts", s)
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("memcache: invalid query in %q: %w", s, err)
+	}
+	if v := query.Get("discovery"); v != "" {
+		spec.Discovery = v
+	}
+	if v := query.Get("dial_timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("memcache: invalid dial_timeout %q: %w", v, err)
+		}
+		spec.DialTimeout = d
+	}
+	if v := query.Get("refresh"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("memcache: invalid refresh %q: %w", v, err)
+		}
+		spec.Refresh = d
+	}
+
+	return spec, nil
+}
+
+// BootstrapError reports why every seed in a BootstrapSpec failed, so a
+// partial DNS outage shows up as individually diagnosable per-seed errors
+// rather than one opaque failure.
+type BootstrapError struct {
+	Failures map[string]error
+}
+
+func (e *BootstrapError) Error() string {
+	parts := make([]string, 0, len(e.Failures))
+	for seed, err := range e.Failures {
+		parts = append(parts, fmt.Sprintf("%s: %s", seed, err))
+	}
+	sort.Strings(parts)
+	return fmt.Sprintf("memcache: all %d seeds failed: %s", len(e.Failures), strings.Join(parts, "; "))
+}
+
+// ResolveConnectionString parses s and resolves the first seed host that
+// returns a valid ClusterConfig. See ResolveBootstrap.
+func ResolveConnectionString(ctx context.Context, s string) (*ClusterConfig, error) {
+	spec, err := ParseConnectionString(s)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveBootstrap(ctx, spec)
+}
+
+// ResolveBootstrap tries each seed host in spec, in order, until one returns
+// a valid ClusterConfig. If every seed fails, the returned error is a
+// *BootstrapError recording each seed's individual failure, so a bad single
+// address doesn't look indistinguishable from every address being down.
+func ResolveBootstrap(ctx context.Context, spec *BootstrapSpec) (*ClusterConfig, error) {
+	scheme, ok := discoverySchemes[spec.Discovery]
+	if !ok {
+		return nil, fmt.Errorf("memcache: unknown discovery mode %q", spec.Discovery)
+	}
+
+	failures := map[string]error{}
+	for _, host := range spec.Hosts {
+		address := scheme + "://" + bootstrapTarget(scheme, host)
+		config, err := Resolve(ctx, address)
+		if err != nil {
+			failures[address] = err
+			continue
+		}
+		return config, nil
+	}
+
+	return nil, &BootstrapError{Failures: failures}
+}
+
+// bootstrapTarget formats host the way its scheme expects: dnssrv resolves a
+// bare SRV name, so attaching BootstrapHost.Port (which only carries a
+// connection-string default, not a DNS port) would corrupt the name passed
+// to LookupSRV. Every other scheme here resolves by host:port.
+func bootstrapTarget(scheme string, host BootstrapHost) string {
+	if scheme == "dnssrv" {
+		return host.Host
+	}
+	return fmt.Sprintf("%s:%d", host.Host, host.Port)
+}
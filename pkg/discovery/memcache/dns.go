@@ -0,0 +1,57 @@
+package memcache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// dnsAResolver resolves a cluster by issuing an A/AAAA lookup against the
+// host portion of address and applying the trailing port to every resulting
+// IP, so "headless.svc:11211" expands to every backend behind that service
+// on port 11211.
+type dnsAResolver struct {
+	interval time.Duration
+
+	watchGroup
+}
+
+// NewDNSAResolver returns a Resolver that expands a "host:port" address into
+// every A/AAAA record behind host. watchInterval controls how often Watch
+// re-resolves; a zero value falls back to defaultWatchInterval.
+func NewDNSAResolver(watchInterval time.Duration) Resolver {
+	return &dnsAResolver{interval: watchInterval}
+}
+
+func (r *dnsAResolver) Resolve(ctx context.Context, address string) (*ClusterConfig, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("dns: address %q must be host:port: %w", address, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("dns: invalid port %q: %w", portStr, err)
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("dns: A/AAAA lookup of %s failed: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("dns: %s has no A/AAAA records", host)
+	}
+
+	config := &ClusterConfig{version: 1}
+	for _, ip := range ips {
+		config.nodes = append(config.nodes, Node{dns: host, ip: ip.IP.String(), port: port})
+	}
+
+	return config, nil
+}
+
+// Watch implements Resolver.
+func (r *dnsAResolver) Watch(ctx context.Context, address string) (<-chan *ClusterConfig, error) {
+	return r.watchGroup.watch(ctx, address, r.interval, r.Resolve)
+}
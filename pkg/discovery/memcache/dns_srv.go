@@ -0,0 +1,54 @@
+package memcache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// dnsSRVResolver resolves a cluster by issuing an SRV query against name,
+// then resolving each SRV target's A/AAAA records to populate Node.ip. name
+// is expected to already be fully qualified, e.g. "_memcached._tcp.svc".
+type dnsSRVResolver struct {
+	interval time.Duration
+
+	watchGroup
+}
+
+// NewDNSSRVResolver returns a Resolver that expands a fully-qualified SRV
+// name into its targets. watchInterval controls how often Watch re-resolves;
+// a zero value falls back to defaultWatchInterval.
+func NewDNSSRVResolver(watchInterval time.Duration) Resolver {
+	return &dnsSRVResolver{interval: watchInterval}
+}
+
+func (r *dnsSRVResolver) Resolve(ctx context.Context, name string) (*ClusterConfig, error) {
+	_, srvs, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, fmt.Errorf("dnssrv: SRV lookup of %s failed: %w", name, err)
+	}
+
+	config := &ClusterConfig{version: 1}
+	for _, srv := range srvs {
+		target := strings.TrimSuffix(srv.Target, ".")
+
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, target)
+		if err != nil {
+			return nil, fmt.Errorf("dnssrv: failed to resolve SRV target %s: %w", target, err)
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("dnssrv: SRV target %s has no A/AAAA records", target)
+		}
+
+		config.nodes = append(config.nodes, Node{dns: target, ip: ips[0].IP.String(), port: int(srv.Port)})
+	}
+
+	return config, nil
+}
+
+// Watch implements Resolver.
+func (r *dnsSRVResolver) Watch(ctx context.Context, name string) (<-chan *ClusterConfig, error) {
+	return r.watchGroup.watch(ctx, name, r.interval, r.Resolve)
+}
@@ -0,0 +1,87 @@
+package memcache
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	outcomeSuccess    = "success"
+	outcomeDialError  = "dial_error"
+	outcomeAuthError  = "auth_error"
+	outcomeWriteError = "write_error"
+	outcomeParseError = "parse_error"
+)
+
+// metrics holds the Prometheus instrumentation for memcachedAutoDiscovery. A
+// nil *metrics is valid and every method on it is a no-op, so callers that
+// construct memcachedAutoDiscovery without a registerer keep working
+// unchanged.
+type metrics struct {
+	resolveDuration *prometheus.HistogramVec
+	parseFailures   *prometheus.CounterVec
+	clusterVersion  *prometheus.GaugeVec
+	nodeCount       *prometheus.GaugeVec
+}
+
+// newMetrics registers the autodiscovery metrics with reg, or returns nil if
+// reg is nil.
+func newMetrics(reg prometheus.Registerer) *metrics {
+	if reg == nil {
+		return nil
+	}
+
+	m := &metrics{
+		resolveDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "memcache",
+			Subsystem: "autodiscovery",
+			Name:      "resolve_duration_seconds",
+			Help:      "Time taken to issue and parse a \"config get cluster\" round trip, by outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"address", "outcome"}),
+		parseFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "memcache",
+			Subsystem: "autodiscovery",
+			Name:      "parse_failures_total",
+			Help:      "Number of \"config get cluster\" responses that failed to parse, by failure reason.",
+		}, []string{"address", "reason"}),
+		clusterVersion: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "memcache",
+			Subsystem: "autodiscovery",
+			Name:      "cluster_version",
+			Help:      "Cluster config version last observed for address.",
+		}, []string{"address"}),
+		nodeCount: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "memcache",
+			Subsystem: "autodiscovery",
+			Name:      "node_count",
+			Help:      "Number of nodes in the cluster config last observed for address.",
+		}, []string{"address"}),
+	}
+
+	reg.MustRegister(m.resolveDuration, m.parseFailures, m.clusterVersion, m.nodeCount)
+	return m
+}
+
+func (m *metrics) observeResolve(address, outcome string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.resolveDuration.WithLabelValues(address, outcome).Observe(d.Seconds())
+}
+
+func (m *metrics) observeParseFailure(address, reason string) {
+	if m == nil {
+		return
+	}
+	m.parseFailures.WithLabelValues(address, reason).Inc()
+}
+
+func (m *metrics) observeClusterConfig(address string, config *ClusterConfig) {
+	if m == nil {
+		return
+	}
+	m.clusterVersion.WithLabelValues(address).Set(float64(config.version))
+	m.nodeCount.WithLabelValues(address).Set(float64(len(config.nodes)))
+}
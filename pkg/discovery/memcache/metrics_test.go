@@ -0,0 +1,44 @@
+package memcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics_NilIsNoOp(t *testing.T) {
+	var m *metrics
+	m.observeResolve("addr", outcomeSuccess, time.Millisecond)
+	m.observeParseFailure("addr", "meta_components")
+	m.observeClusterConfig("addr", &ClusterConfig{version: 1, nodes: []Node{{}}})
+}
+
+func TestMetrics_ScrapeAfterSuccessAndFailure(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := newMetrics(reg)
+
+	m.observeResolve("addr:11211", outcomeSuccess, 5*time.Millisecond)
+	m.observeClusterConfig("addr:11211", &ClusterConfig{version: 3, nodes: []Node{{}, {}}})
+
+	m.observeResolve("addr:11211", outcomeDialError, time.Millisecond)
+	m.observeParseFailure("addr:11211", "bad_port")
+
+	if count := testutil.CollectAndCount(m.resolveDuration); count != 2 {
+		t.Fatalf("expected 2 resolve_duration_seconds series (success, dial_error), got %d", count)
+	}
+	if got := testutil.ToFloat64(m.parseFailures.WithLabelValues("addr:11211", "bad_port")); got != 1 {
+		t.Fatalf("expected 1 bad_port parse failure, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.clusterVersion.WithLabelValues("addr:11211")); got != 3 {
+		t.Fatalf("expected cluster version 3, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.nodeCount.WithLabelValues("addr:11211")); got != 2 {
+		t.Fatalf("expected node count 2, got %v", got)
+	}
+
+	if _, err := reg.Gather(); err != nil {
+		t.Fatalf("failed to scrape registry: %s", err)
+	}
+}
@@ -0,0 +1,70 @@
+package memcache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+func init() {
+	Register("autodiscovery+tcp", &memcachedAutoDiscovery{watchInterval: defaultWatchInterval})
+	Register("dnssrv", NewDNSSRVResolver(defaultWatchInterval))
+	Register("dns", NewDNSAResolver(defaultWatchInterval))
+	Register("static", NewStaticResolver(defaultWatchInterval))
+}
+
+var (
+	registryMtx sync.RWMutex
+	registry    = map[string]Resolver{}
+)
+
+// Register makes a Resolver available under scheme, so Resolve and Watch can
+// dispatch addresses of the form "scheme://..." to it without the caller
+// needing to know the concrete backend. Register panics if scheme is already
+// registered, analogous to database/sql.Register.
+func Register(scheme string, r Resolver) {
+	registryMtx.Lock()
+	defer registryMtx.Unlock()
+	if _, dup := registry[scheme]; dup {
+		panic("memcache: Register called twice for scheme " + scheme)
+	}
+	registry[scheme] = r
+}
+
+func lookup(address string) (r Resolver, rest string, err error) {
+	scheme, rest, ok := strings.Cut(address, "://")
+	if !ok {
+		return nil, "", fmt.Errorf("memcache: address %q has no scheme", address)
+	}
+
+	registryMtx.RLock()
+	r, ok = registry[scheme]
+	registryMtx.RUnlock()
+	if !ok {
+		return nil, "", fmt.Errorf("memcache: no resolver registered for scheme %q", scheme)
+	}
+	return r, rest, nil
+}
+
+// Resolve parses the scheme off address (e.g. "static://h1:11211,h2:11211")
+// and delegates to whichever Resolver is registered for it. Supported
+// schemes out of the box are "autodiscovery+tcp", "dnssrv", "dns" and
+// "static"; callers can widen that set with Register.
+func Resolve(ctx context.Context, address string) (*ClusterConfig, error) {
+	r, rest, err := lookup(address)
+	if err != nil {
+		return nil, err
+	}
+	return r.Resolve(ctx, rest)
+}
+
+// Watch is the streaming counterpart to Resolve: it dispatches on scheme and
+// delegates to the matching Resolver's Watch.
+func Watch(ctx context.Context, address string) (<-chan *ClusterConfig, error) {
+	r, rest, err := lookup(address)
+	if err != nil {
+		return nil, err
+	}
+	return r.Watch(ctx, rest)
+}
@@ -3,11 +3,14 @@ package memcache
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type ClusterConfig struct {
@@ -27,38 +30,129 @@ type Resolver interface {
 	// qtype is the query type. Accepted values are `dns` for A/AAAA lookup and `dnssrv` for SRV lookup.
 	// If scheme is passed through name, it is preserved on IP results.
 	Resolve(ctx context.Context, address string) (*ClusterConfig, error)
+
+	// Watch keeps re-resolving address in the background and pushes a new
+	// ClusterConfig on the returned channel whenever the cluster version
+	// increases or the node set changes. The channel is closed once ctx is
+	// cancelled. Callers that no longer need updates should cancel ctx so the
+	// background goroutine can be reclaimed once all subscribers have gone.
+	Watch(ctx context.Context, address string) (<-chan *ClusterConfig, error)
+}
+
+// AuthMechanism selects the SASL mechanism used to authenticate a discovery
+// connection.
+type AuthMechanism string
+
+// AuthMechanismPlain is the only AuthMechanism supported today; room is left
+// for e.g. SCRAM as ElastiCache adds support for it.
+const AuthMechanismPlain AuthMechanism = "PLAIN"
+
+// AutoDiscoveryConfig configures a memcachedAutoDiscovery Resolver.
+type AutoDiscoveryConfig struct {
+	DialTimeout   time.Duration
+	WatchInterval time.Duration
+
+	// TLSConfig dials the discovery endpoint with TLS when set, as required
+	// by ElastiCache Serverless and by memcached deployments fronted by an
+	// in-cluster mTLS mesh.
+	TLSConfig *tls.Config
+
+	// Username, when set, authenticates the discovery connection with
+	// Password before "config get cluster" is issued. Password is not
+	// required to be non-empty.
+	Username string
+	Password string
+	// AuthMechanism defaults to AuthMechanismPlain when Username is set.
+	AuthMechanism AuthMechanism
 }
 
 type memcachedAutoDiscovery struct {
-	dialTimeout time.Duration
+	dialTimeout   time.Duration
+	watchInterval time.Duration
+	tlsConfig     *tls.Config
+	username      string
+	password      string
+	authMechanism AuthMechanism
+	metrics       *metrics
+
+	watchGroup
+}
+
+// NewMemcachedAutoDiscovery returns a Resolver that talks the ElastiCache
+// "config get cluster" protocol. reg may be nil, in which case no metrics
+// are registered.
+func NewMemcachedAutoDiscovery(cfg AutoDiscoveryConfig, reg prometheus.Registerer) Resolver {
+	return &memcachedAutoDiscovery{
+		dialTimeout:   cfg.DialTimeout,
+		watchInterval: cfg.WatchInterval,
+		tlsConfig:     cfg.TLSConfig,
+		username:      cfg.Username,
+		password:      cfg.Password,
+		authMechanism: cfg.AuthMechanism,
+		metrics:       newMetrics(reg),
+	}
+}
+
+// Watch implements Resolver.
+func (s *memcachedAutoDiscovery) Watch(ctx context.Context, address string) (<-chan *ClusterConfig, error) {
+	return s.watchGroup.watch(ctx, address, s.watchInterval, s.Resolve)
 }
 
 func (s *memcachedAutoDiscovery) Resolve(ctx context.Context, address string) (config *ClusterConfig, err error) {
-	conn, err := net.DialTimeout("tcp", address, s.dialTimeout)
+	start := time.Now()
+
+	conn, err := s.dial(address)
 	if err != nil {
+		s.metrics.observeResolve(address, outcomeDialError, time.Since(start))
 		return nil, err
 	}
 	defer func() {
-		err = conn.Close()
+		// authenticate returns a nil conn when it fails after discarding the
+		// original connection; there is nothing left to close then.
+		if conn == nil {
+			return
+		}
+		if closeErr := conn.Close(); err == nil {
+			err = closeErr
+		}
 	}()
 
-	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	conn, rw, err := s.authenticate(conn, address)
+	if err != nil {
+		s.metrics.observeResolve(address, outcomeAuthError, time.Since(start))
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+
 	if _, err := fmt.Fprintf(rw, "config get cluster\n"); err != nil {
+		s.metrics.observeResolve(address, outcomeWriteError, time.Since(start))
 		return nil, err
 	}
 	if err := rw.Flush(); err != nil {
+		s.metrics.observeResolve(address, outcomeWriteError, time.Since(start))
 		return nil, err
 	}
 
-	config, err = s.parseConfig(rw.Reader)
+	config, err = s.parseConfig(address, rw.Reader)
 	if err != nil {
+		s.metrics.observeResolve(address, outcomeParseError, time.Since(start))
 		return nil, err
 	}
 
+	s.metrics.observeResolve(address, outcomeSuccess, time.Since(start))
+	s.metrics.observeClusterConfig(address, config)
 	return config, err
 }
 
-func (s *memcachedAutoDiscovery) parseConfig(reader *bufio.Reader) (*ClusterConfig, error) {
+// dial opens the discovery connection, using TLS when s.tlsConfig is set.
+func (s *memcachedAutoDiscovery) dial(address string) (net.Conn, error) {
+	if s.tlsConfig != nil {
+		dialer := &net.Dialer{Timeout: s.dialTimeout}
+		return tls.DialWithDialer(dialer, "tcp", address, s.tlsConfig)
+	}
+	return net.DialTimeout("tcp", address, s.dialTimeout)
+}
+
+func (s *memcachedAutoDiscovery) parseConfig(address string, reader *bufio.Reader) (*ClusterConfig, error) {
 	clusterConfig := new(ClusterConfig)
 
 	configMeta, err := reader.ReadString('\n')
@@ -70,6 +164,7 @@ func (s *memcachedAutoDiscovery) parseConfig(reader *bufio.Reader) (*ClusterConf
 	// First line should be "CONFIG cluster 0 [length-of-payload-]
 	configMetaComponents := strings.Split(configMeta, " ")
 	if len(configMetaComponents) != 4 {
+		s.metrics.observeParseFailure(address, "meta_components")
 		return nil, fmt.Errorf("expected 4 components in config metadata, and recieved %d, meta: %s", len(configMetaComponents), configMeta)
 	}
 
@@ -90,16 +185,19 @@ func (s *memcachedAutoDiscovery) parseConfig(reader *bufio.Reader) (*ClusterConf
 	}
 
 	if len(configVersion)+len(nodes) != configSize {
+		s.metrics.observeParseFailure(address, "size_mismatch")
 		return nil, fmt.Errorf("expected %d in config payload, but got %d instead.", configSize, len(configVersion)+len(nodes))
 	}
 
 	for _, host := range strings.Split(strings.TrimSpace(nodes), " ") {
 		dnsIpPort := strings.Split(host, "|")
 		if len(dnsIpPort) != 3 {
+			s.metrics.observeParseFailure(address, "bad_node_format")
 			return nil, fmt.Errorf("node not in expected format: %s", dnsIpPort)
 		}
 		port, err := strconv.Atoi(dnsIpPort[2])
 		if err != nil {
+			s.metrics.observeParseFailure(address, "bad_port")
 			return nil, fmt.Errorf("failed to parse port: %s, err: %s", dnsIpPort, err)
 		}
 		clusterConfig.nodes = append(clusterConfig.nodes, Node{dns: dnsIpPort[0], ip: dnsIpPort[1], port: port})
@@ -0,0 +1,122 @@
+package memcache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// newScriptedServer is a fake ElastiCache-style TCP server: it accepts one
+// connection per entry in responses, in order, and hands each connection to
+// the corresponding response func. Once responses is exhausted, it keeps
+// serving the last one, so a caller that dials again after the script ends
+// doesn't hang waiting for a connection nobody will ever accept.
+func newScriptedServer(t *testing.T, responses ...func(net.Conn)) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			respond := responses[len(responses)-1]
+			if i < len(responses) {
+				respond = responses[i]
+			}
+			respond(conn)
+			conn.Close()
+		}
+	}()
+
+	return ln
+}
+
+// configFrame writes a well-formed "config get cluster" response.
+func configFrame(version int, nodes string) func(net.Conn) {
+	return func(conn net.Conn) {
+		versionLine := fmt.Sprintf("%d\n", version)
+		nodesLine := nodes + "\n"
+		size := len(versionLine) + len(nodesLine)
+		fmt.Fprintf(conn, "CONFIG cluster 0 %d\n%s%s", size, versionLine, nodesLine)
+	}
+}
+
+// disconnect accepts the connection and immediately closes it without
+// writing anything, simulating a dropped discovery endpoint.
+func disconnect() func(net.Conn) {
+	return func(net.Conn) {}
+}
+
+// malformedFrame writes a config-metadata line that doesn't have the
+// expected 4 components.
+func malformedFrame() func(net.Conn) {
+	return func(conn net.Conn) {
+		fmt.Fprintf(conn, "NOT VALID\n")
+	}
+}
+
+func TestMemcachedAutoDiscovery_Resolve(t *testing.T) {
+	ln := newScriptedServer(t, configFrame(1, "node1.cache|10.0.0.1|11211"))
+	r := &memcachedAutoDiscovery{dialTimeout: time.Second}
+
+	config, err := r.Resolve(context.Background(), ln.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if config.version != 1 {
+		t.Fatalf("expected version 1, got %d", config.version)
+	}
+	if len(config.nodes) != 1 || config.nodes[0].ip != "10.0.0.1" || config.nodes[0].port != 11211 {
+		t.Fatalf("unexpected nodes: %+v", config.nodes)
+	}
+}
+
+func TestMemcachedAutoDiscovery_Resolve_VersionBump(t *testing.T) {
+	ln := newScriptedServer(t,
+		configFrame(1, "node1.cache|10.0.0.1|11211"),
+		configFrame(2, "node1.cache|10.0.0.1|11211 node2.cache|10.0.0.2|11211"),
+	)
+	r := &memcachedAutoDiscovery{dialTimeout: time.Second}
+
+	first, err := r.Resolve(context.Background(), ln.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error on first resolve: %s", err)
+	}
+	if first.version != 1 || len(first.nodes) != 1 {
+		t.Fatalf("unexpected first config: %+v", first)
+	}
+
+	second, err := r.Resolve(context.Background(), ln.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error on second resolve: %s", err)
+	}
+	if second.version != 2 || len(second.nodes) != 2 {
+		t.Fatalf("unexpected second config: %+v", second)
+	}
+}
+
+func TestMemcachedAutoDiscovery_Resolve_Disconnect(t *testing.T) {
+	ln := newScriptedServer(t, disconnect())
+	r := &memcachedAutoDiscovery{dialTimeout: time.Second}
+
+	if _, err := r.Resolve(context.Background(), ln.Addr().String()); err == nil {
+		t.Fatal("expected an error when the server disconnects without responding")
+	}
+}
+
+func TestMemcachedAutoDiscovery_Resolve_MalformedFrame(t *testing.T) {
+	ln := newScriptedServer(t, malformedFrame())
+	r := &memcachedAutoDiscovery{dialTimeout: time.Second}
+
+	if _, err := r.Resolve(context.Background(), ln.Addr().String()); err == nil {
+		t.Fatal("expected an error for a malformed config frame")
+	}
+}
@@ -0,0 +1,48 @@
+package memcache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// staticResolver serves a fixed, comma-separated list of "host:port" pairs
+// without performing any lookup, for deployments that manage their node list
+// out of band, e.g. "static://h1:11211,h2:11211".
+type staticResolver struct {
+	interval time.Duration
+
+	watchGroup
+}
+
+// NewStaticResolver returns a Resolver that serves a fixed node list without
+// performing any lookup. watchInterval controls how often Watch re-checks
+// the (unchanging) list; a zero value falls back to defaultWatchInterval.
+func NewStaticResolver(watchInterval time.Duration) Resolver {
+	return &staticResolver{interval: watchInterval}
+}
+
+func (r *staticResolver) Resolve(ctx context.Context, address string) (*ClusterConfig, error) {
+	config := &ClusterConfig{version: 1}
+	for _, hostport := range strings.Split(address, ",") {
+		host, portStr, err := net.SplitHostPort(hostport)
+		if err != nil {
+			return nil, fmt.Errorf("static: %q must be host:port: %w", hostport, err)
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("static: invalid port in %q: %w", hostport, err)
+		}
+		config.nodes = append(config.nodes, Node{dns: host, ip: host, port: port})
+	}
+
+	return config, nil
+}
+
+// Watch implements Resolver.
+func (r *staticResolver) Watch(ctx context.Context, address string) (<-chan *ClusterConfig, error) {
+	return r.watchGroup.watch(ctx, address, r.interval, r.Resolve)
+}
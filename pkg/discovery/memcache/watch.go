@@ -0,0 +1,198 @@
+package memcache
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultWatchInterval is how often a watchGroup re-resolves while idle.
+	defaultWatchInterval = 30 * time.Second
+	watchBackoffMin      = 500 * time.Millisecond
+	watchBackoffMax      = 30 * time.Second
+)
+
+// watchGroup implements the polling half of Resolver.Watch, shared by every
+// backend registered in this package: it deduplicates concurrent Watch calls
+// for the same address down to a single background poll loop, regardless of
+// how many subscribers are attached, and fans out updates to all of them.
+//
+// mtx guards both the watchers map and every addressWatcher reachable from
+// it (its subscriber set, refs and last), so a subscriber can never be added
+// to, or removed from, a watcher that's concurrently being torn down.
+type watchGroup struct {
+	mtx      sync.Mutex
+	watchers map[string]*addressWatcher
+}
+
+// resolveFunc is the shape of Resolver.Resolve, passed in by the caller so
+// watchGroup stays agnostic to how a given backend actually resolves.
+type resolveFunc func(ctx context.Context, address string) (*ClusterConfig, error)
+
+// addressWatcher is the single background poll loop for one address, shared
+// by every subscriber currently watching it. It outlives any individual
+// subscriber's ctx and is only torn down once refs drops to zero.
+type addressWatcher struct {
+	subscribers map[chan *ClusterConfig]struct{}
+	refs        int
+	last        *ClusterConfig
+	stop        context.CancelFunc
+}
+
+// watch registers a new subscriber for address, starting its background poll
+// loop on first use. The loop's lifetime is independent of ctx: ctx only
+// governs when this particular subscriber's channel is closed, per the Watch
+// contract. The loop itself keeps running, shared by any other subscriber,
+// until the last subscriber for address unsubscribes. A subscriber that
+// joins a loop which has already published receives that last config
+// straight away.
+func (g *watchGroup) watch(ctx context.Context, address string, interval time.Duration, resolve resolveFunc) (<-chan *ClusterConfig, error) {
+	g.mtx.Lock()
+	if g.watchers == nil {
+		g.watchers = map[string]*addressWatcher{}
+	}
+	w, ok := g.watchers[address]
+	if !ok {
+		runCtx, cancel := context.WithCancel(context.Background())
+		w = &addressWatcher{subscribers: map[chan *ClusterConfig]struct{}{}, stop: cancel}
+		g.watchers[address] = w
+		go g.run(runCtx, address, w, interval, resolve)
+	}
+
+	ch := make(chan *ClusterConfig, 1)
+	if w.last != nil {
+		ch <- w.last
+	}
+	w.subscribers[ch] = struct{}{}
+	w.refs++
+	g.mtx.Unlock()
+
+	go g.unsubscribeOnDone(ctx, address, w, ch)
+
+	return ch, nil
+}
+
+// unsubscribeOnDone waits for a single subscriber's ctx to be cancelled,
+// then detaches and closes its channel. Once the last subscriber for
+// address has gone, it stops the shared background loop and removes address
+// from the registry so the next Watch call starts a fresh one.
+func (g *watchGroup) unsubscribeOnDone(ctx context.Context, address string, w *addressWatcher, ch chan *ClusterConfig) {
+	<-ctx.Done()
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if _, ok := w.subscribers[ch]; !ok {
+		// Already torn down by run()'s shutdown, e.g. if the loop exited on
+		// its own.
+		return
+	}
+	delete(w.subscribers, ch)
+	close(ch)
+	w.refs--
+
+	if w.refs == 0 {
+		if g.watchers[address] == w {
+			delete(g.watchers, address)
+		}
+		w.stop()
+	}
+}
+
+// run is the single goroutine per address that re-resolves on interval,
+// applying exponential backoff with jitter when resolve fails, and emits
+// updates only when the version advances or the node set differs from the
+// last config published. It runs until ctx is cancelled, which only happens
+// once the last subscriber has unsubscribed.
+func (g *watchGroup) run(ctx context.Context, address string, w *addressWatcher, interval time.Duration, resolve resolveFunc) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	backoff := watchBackoffMin
+
+	defer g.shutdown(address, w)
+
+	for {
+		config, err := resolve(ctx, address)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(jitter(backoff)):
+			}
+			backoff *= 2
+			if backoff > watchBackoffMax {
+				backoff = watchBackoffMax
+			}
+			continue
+		}
+		backoff = watchBackoffMin
+
+		g.publish(w, config)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// publish sends config to every subscriber of w if it differs from the last
+// config published, coalescing with whatever update a subscriber hasn't
+// consumed yet rather than blocking the discovery loop.
+func (g *watchGroup) publish(w *addressWatcher, config *ClusterConfig) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if !changed(w.last, config) {
+		return
+	}
+	w.last = config
+
+	for ch := range w.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- config
+	}
+}
+
+// shutdown is run's deferred cleanup. It's a no-op in the common case, where
+// unsubscribeOnDone already removed address from the registry once refs hit
+// zero; it only has work to do if the loop exited some other way, in which
+// case it closes any subscribers still attached.
+func (g *watchGroup) shutdown(address string, w *addressWatcher) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if g.watchers[address] == w {
+		delete(g.watchers, address)
+	}
+	for ch := range w.subscribers {
+		delete(w.subscribers, ch)
+		close(ch)
+	}
+}
+
+// changed reports whether next should be published: the cluster has never
+// been seen before, its version increased, or its node set differs.
+func changed(last, next *ClusterConfig) bool {
+	if last == nil {
+		return true
+	}
+	if next.version > last.version {
+		return true
+	}
+	return !reflect.DeepEqual(last.nodes, next.nodes)
+}
+
+// jitter returns d plus up to 50% random jitter, so concurrently backing off
+// watchers don't all retry in lockstep.
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
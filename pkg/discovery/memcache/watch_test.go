@@ -0,0 +1,130 @@
+package memcache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitForConfig(t *testing.T, ch <-chan *ClusterConfig) *ClusterConfig {
+	t.Helper()
+	select {
+	case config := <-ch:
+		return config
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a ClusterConfig")
+		return nil
+	}
+}
+
+func TestWatchGroup_PerSubscriberLifecycle(t *testing.T) {
+	var g watchGroup
+	resolve := func(ctx context.Context, address string) (*ClusterConfig, error) {
+		return &ClusterConfig{version: 1, nodes: []Node{{dns: "a", ip: "10.0.0.1", port: 11211}}}, nil
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+
+	ch1, err := g.watch(ctx1, "addr", time.Millisecond, resolve)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ch2, err := g.watch(ctx2, "addr", time.Millisecond, resolve)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	waitForConfig(t, ch1)
+	waitForConfig(t, ch2)
+
+	cancel1()
+
+	select {
+	case _, ok := <-ch1:
+		if ok {
+			t.Fatal("expected ch1 to be closed after its own ctx was cancelled")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ch1 was never closed after its ctx was cancelled")
+	}
+
+	select {
+	case _, ok := <-ch2:
+		if !ok {
+			t.Fatal("ch2 was closed even though its own ctx is still live")
+		}
+	case <-time.After(50 * time.Millisecond):
+		// No new update yet; the point is ch2 must still be open.
+	}
+}
+
+func TestWatchGroup_StopsLoopOnceLastSubscriberGone(t *testing.T) {
+	var g watchGroup
+	resolve := func(ctx context.Context, address string) (*ClusterConfig, error) {
+		return &ClusterConfig{version: 1}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := g.watch(ctx, "addr", time.Millisecond, resolve)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	waitForConfig(t, ch)
+
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected the channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel was never closed")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		g.mtx.Lock()
+		_, present := g.watchers["addr"]
+		g.mtx.Unlock()
+		if !present {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("watcher entry was never removed once the last subscriber unsubscribed")
+}
+
+func TestWatchGroup_OnlyPublishesOnChange(t *testing.T) {
+	var g watchGroup
+	var calls atomic.Int64
+	resolve := func(ctx context.Context, address string) (*ClusterConfig, error) {
+		calls.Add(1)
+		// Same version and nodes on every call; only the first resolve
+		// should ever reach the subscriber.
+		return &ClusterConfig{version: 1, nodes: []Node{{dns: "a", ip: "10.0.0.1", port: 11211}}}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := g.watch(ctx, "addr", 5*time.Millisecond, resolve)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	waitForConfig(t, ch)
+
+	select {
+	case config := <-ch:
+		t.Fatalf("expected no further publishes for an unchanged config, got %+v", config)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if n := calls.Load(); n < 2 {
+		t.Fatalf("expected resolve to have been retried at least once, got %d calls", n)
+	}
+}